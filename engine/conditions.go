@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+// IncidentContext carries the location and extra data for a single match
+// produced by a Conditional.
+type IncidentContext struct {
+	FileURI    string                 `yaml:"fileURI,omitempty"`
+	LineNumber *int                   `yaml:"lineNumber,omitempty"`
+	Variables  map[string]interface{} `yaml:"variables,omitempty"`
+}
+
+// ChainTemplate is the value stored under a chain's As name so that later
+// conditions can reference it via From.
+type ChainTemplate struct {
+	Extras map[string]interface{}
+}
+
+// ConditionContext is threaded through condition evaluation so that
+// chainable conditions can read values produced by earlier conditions in
+// the same rule.
+type ConditionContext struct {
+	Tags     map[string]bool
+	Template map[string]ChainTemplate
+}
+
+// ConditionResponse is returned by a Conditional's Evaluate call.
+type ConditionResponse struct {
+	Matched bool
+	// Incidents are the locations where this condition matched.
+	Incidents []IncidentContext
+	// TemplateContext is exported to the enclosing ConditionEntry's As
+	// name, if one is set, so that later From references can consume it.
+	TemplateContext map[string]interface{}
+}
+
+// Conditional is implemented by provider-specific condition types as well
+// as by the boolean composites (AndCondition, OrCondition, NotCondition)
+// defined in this package.
+type Conditional interface {
+	Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error)
+	Ignorable() bool
+}
+
+// ConditionEntry is a single entry in a When tree. From/As implement the
+// chain-context plumbing: As names the value this entry produces (if it
+// matches) for later entries to consume via From.
+type ConditionEntry struct {
+	From                   string
+	As                     string
+	ProviderSpecificConfig Conditional
+}
+
+// evaluate runs the entry's underlying Conditional and, when the entry
+// matches and declares an As name, records its TemplateContext in condCtx
+// so that sibling entries evaluated afterwards can resolve it via From. An
+// As on an entry wrapping a NotCondition is never recorded.
+func (c ConditionEntry) evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	response, err := c.ProviderSpecificConfig.Evaluate(ctx, log, condCtx)
+	if err != nil {
+		return response, err
+	}
+	if _, isNot := c.ProviderSpecificConfig.(NotCondition); !isNot && c.As != "" && response.Matched {
+		condCtx.Template[c.As] = ChainTemplate{Extras: response.TemplateContext}
+	}
+	return response, nil
+}
+
+// AndCondition matches when every one of its Conditions matches.
+type AndCondition struct {
+	Conditions []ConditionEntry
+}
+
+func (a AndCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	matched := true
+	incidents := []IncidentContext{}
+	templateContext := map[string]interface{}{}
+	for _, cond := range a.Conditions {
+		response, err := cond.evaluate(ctx, log, condCtx)
+		if err != nil {
+			return ConditionResponse{}, err
+		}
+		if !response.Matched {
+			matched = false
+		}
+		incidents = append(incidents, response.Incidents...)
+		for k, v := range response.TemplateContext {
+			templateContext[k] = v
+		}
+	}
+	return ConditionResponse{
+		Matched:         matched,
+		Incidents:       incidents,
+		TemplateContext: templateContext,
+	}, nil
+}
+
+func (a AndCondition) Ignorable() bool {
+	for _, cond := range a.Conditions {
+		if !cond.ProviderSpecificConfig.Ignorable() {
+			return false
+		}
+	}
+	return true
+}
+
+// OrCondition matches when at least one of its Conditions matches.
+type OrCondition struct {
+	Conditions []ConditionEntry
+}
+
+func (o OrCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	matched := false
+	incidents := []IncidentContext{}
+	templateContext := map[string]interface{}{}
+	for _, cond := range o.Conditions {
+		response, err := cond.evaluate(ctx, log, condCtx)
+		if err != nil {
+			return ConditionResponse{}, err
+		}
+		if response.Matched {
+			matched = true
+		}
+		incidents = append(incidents, response.Incidents...)
+		for k, v := range response.TemplateContext {
+			templateContext[k] = v
+		}
+	}
+	return ConditionResponse{
+		Matched:         matched,
+		Incidents:       incidents,
+		TemplateContext: templateContext,
+	}, nil
+}
+
+func (o OrCondition) Ignorable() bool {
+	for _, cond := range o.Conditions {
+		if !cond.ProviderSpecificConfig.Ignorable() {
+			return false
+		}
+	}
+	return true
+}
+
+// NotCondition matches when its single inner Condition does not. It never
+// produces incidents or As template context of its own - a Not that
+// matches means the inner condition found nothing, so there is nothing to
+// report and nothing to chain from. Any From inside the inner Condition
+// resolves against the scope enclosing the NotCondition; since a Not has
+// no siblings of its own, there is nothing else for it to resolve against.
+type NotCondition struct {
+	Condition ConditionEntry
+}
+
+func (n NotCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	response, err := n.Condition.ProviderSpecificConfig.Evaluate(ctx, log, condCtx)
+	if err != nil {
+		return ConditionResponse{}, err
+	}
+	return ConditionResponse{Matched: !response.Matched}, nil
+}
+
+func (n NotCondition) Ignorable() bool {
+	return n.Condition.ProviderSpecificConfig.Ignorable()
+}
@@ -0,0 +1,277 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"text/template"
+
+	"github.com/go-logr/logr"
+)
+
+// RuleResponse is the outcome of evaluating a single Rule against the
+// providers: whether it matched and, if so, the incidents, message and
+// tags it produced.
+type RuleResponse struct {
+	RuleID    string
+	Matched   bool
+	Incidents []IncidentContext
+	Message   string
+	Tags      []string
+}
+
+// RuleEngine evaluates RuleSets concurrently, bounding the number of rules
+// in flight at once to workers.
+type RuleEngine struct {
+	logger          logr.Logger
+	workers         int
+	strict          bool
+	strictTemplates bool
+	funcMap         template.FuncMap
+}
+
+// Option configures a RuleEngine at construction time.
+type Option func(*RuleEngine)
+
+// WithStrict makes RunRules reject the entire run when static validation
+// (see ValidateRuleSets) finds any invalid rule, instead of logging the
+// bad rule and skipping just it.
+func WithStrict(strict bool) Option {
+	return func(r *RuleEngine) {
+		r.strict = strict
+	}
+}
+
+// WithStrictTemplates makes a Message.Text or Perform.Tag template fail
+// the rule when it references a chain value that wasn't produced, instead
+// of silently rendering Go's "<no value>".
+func WithStrictTemplates(strict bool) Option {
+	return func(r *RuleEngine) {
+		r.strictTemplates = strict
+	}
+}
+
+// CreateRuleEngine builds a RuleEngine that will run up to workers rules
+// concurrently. ctx is not used; in particular it does not bound
+// individual RunRules calls.
+func CreateRuleEngine(ctx context.Context, workers int, log logr.Logger, opts ...Option) RuleEngine {
+	if workers <= 0 {
+		workers = 1
+	}
+	r := RuleEngine{
+		logger:  log.WithName("ruleEngine"),
+		workers: workers,
+		funcMap: template.FuncMap{},
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+// RegisterTemplateFunc makes fn available, as name, to every Message.Text
+// and Perform.Tag template this engine renders, on top of the built-in
+// helpers (join, basename, relpath, quote, default). fn follows the same
+// rules as text/template.Funcs: a func returning one value, or a value and
+// an error.
+func (r *RuleEngine) RegisterTemplateFunc(name string, fn any) {
+	if r.funcMap == nil {
+		r.funcMap = template.FuncMap{}
+	}
+	r.funcMap[name] = fn
+}
+
+// evaluateRuleSets evaluates every Rule's When tree in ruleSets across the
+// engine's worker pool, the same way regardless of caller, and invokes
+// handle once per rule that evaluated without error. handle may be called
+// concurrently from any of the engine's workers.
+func (r *RuleEngine) evaluateRuleSets(ctx context.Context, ruleSets []RuleSet, handle func(rule Rule, condCtx ConditionContext, response ConditionResponse)) {
+	var rules []Rule
+	for _, rs := range ruleSets {
+		rules = append(rules, rs.Rules...)
+	}
+
+	ruleCh := make(chan Rule, len(rules))
+	for _, rule := range rules {
+		ruleCh <- rule
+	}
+	close(ruleCh)
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rule := range ruleCh {
+				condCtx := ConditionContext{
+					Template: make(map[string]ChainTemplate),
+				}
+				response, err := processRule(ctx, rule, condCtx, r.logger)
+				if err != nil {
+					r.logger.Error(err, "unable to evaluate rule", "ruleID", rule.RuleID)
+					continue
+				}
+				handle(rule, condCtx, response)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// RunRules evaluates every Rule in every RuleSet and returns the responses
+// for the rules that matched, along with the errors that rejected the run
+// (validation errors in strict mode; otherwise invalid rules are logged and
+// skipped, not returned). It is a thin wrapper over RunRulesWithOpts that
+// runs every rule.
+func (r *RuleEngine) RunRules(ctx context.Context, ruleSets []RuleSet) ([]RuleResponse, []error) {
+	responses, errs, _ := r.RunRulesWithOpts(ctx, ruleSets, ExecuteOpts{})
+	return responses, errs
+}
+
+// RunRulesWithOpts evaluates the rules ExecuteOpts.Targets selects (or
+// every rule, if Targets is empty) and returns their responses, the errors
+// that rejected the run, and a TargetResult per target so that a target
+// selecting zero rules - typically a CI misconfiguration - can be
+// detected. Rules that fail static validation (see ValidateRuleSets) are
+// logged and skipped - or, in strict mode, cause the whole run to be
+// rejected up front with the validation errors returned - rather than
+// surfacing as a confusing per-provider error mid-run.
+// Validation runs before targets are applied, so one rule's malformed
+// template can't abort targeting (and so Tag-selector evaluation, which
+// renders a rule's own Perform.Tag, never has to run against a template
+// that's already known to be broken). Filtering then happens before rules
+// are dispatched to the worker pool, so the concurrency budget is only
+// spent on selected rules.
+func (r *RuleEngine) RunRulesWithOpts(ctx context.Context, ruleSets []RuleSet, opts ExecuteOpts) ([]RuleResponse, []error, []TargetResult) {
+	if errs := ValidateRuleSets(ruleSets, r.funcMap); len(errs) > 0 {
+		if r.strict {
+			return nil, errs, nil
+		}
+		for _, err := range errs {
+			r.logger.Error(err, "skipping rule that failed static validation")
+		}
+		ruleSets = dropInvalidRules(ruleSets, r.funcMap)
+	}
+
+	ruleSets, targetResults, err := filterRuleSets(ruleSets, opts.Targets, r.funcMap)
+	if err != nil {
+		return nil, []error{err}, targetResults
+	}
+
+	var mu sync.Mutex
+	var responses []RuleResponse
+	r.evaluateRuleSets(ctx, ruleSets, func(rule Rule, condCtx ConditionContext, response ConditionResponse) {
+		if !response.Matched {
+			return
+		}
+		message, tags, err := resolvePerform(rule.Perform, templateDataFromContext(condCtx), r.funcMap, r.strictTemplates)
+		if err != nil {
+			r.logger.Error(err, "unable to resolve rule message/tags", "ruleID", rule.RuleID)
+			return
+		}
+		mu.Lock()
+		responses = append(responses, RuleResponse{
+			RuleID:    rule.RuleID,
+			Matched:   response.Matched,
+			Incidents: response.Incidents,
+			Message:   message,
+			Tags:      tags,
+		})
+		mu.Unlock()
+	})
+
+	return responses, nil, targetResults
+}
+
+// dropInvalidRules returns a copy of ruleSets with any rule that fails
+// ValidateRule removed, so that RunRules can still make progress on the
+// rules that are well-formed.
+func dropInvalidRules(ruleSets []RuleSet, funcMap template.FuncMap) []RuleSet {
+	out := make([]RuleSet, 0, len(ruleSets))
+	for _, rs := range ruleSets {
+		kept := rs
+		kept.Rules = make([]Rule, 0, len(rs.Rules))
+		for _, rule := range rs.Rules {
+			if len(ValidateRule(rule, funcMap)) == 0 {
+				kept.Rules = append(kept.Rules, rule)
+			}
+		}
+		out = append(out, kept)
+	}
+	return out
+}
+
+// RulePlan is what a single Rule would do if RunRules were invoked against
+// the current providers, without actually performing it.
+type RulePlan struct {
+	RuleID          string
+	Matched         bool
+	Incidents       []IncidentContext
+	Message         string
+	Tags            []string
+	TemplateContext map[string]interface{}
+}
+
+// PlanResult is the outcome of PlanRules.
+type PlanResult struct {
+	Plans []RulePlan
+	// Errors are the static validation errors (see ValidateRuleSets) found
+	// in ruleSets. The rules they name are excluded from Plans, the same
+	// way RunRulesWithOpts drops them rather than running them - unless the
+	// engine is in strict mode, in which case Plans is empty and Errors is
+	// the whole reason why.
+	Errors []error
+}
+
+// PlanRules evaluates every Rule's When tree exactly as RunRulesWithOpts
+// would - on the same worker pool, with the same chain-context semantics
+// and the same static validation (see ValidateRuleSets) - but never
+// applies Perform: it resolves the Message and tags a matched rule would
+// have produced without emitting them anywhere. This lets users preview a
+// large ruleset, or diff rule behavior across changes, before committing
+// to a real run.
+func (r *RuleEngine) PlanRules(ctx context.Context, ruleSets []RuleSet) PlanResult {
+	errs := ValidateRuleSets(ruleSets, r.funcMap)
+	if len(errs) > 0 {
+		if r.strict {
+			return PlanResult{Errors: errs}
+		}
+		for _, err := range errs {
+			r.logger.Error(err, "skipping rule that failed static validation")
+		}
+		ruleSets = dropInvalidRules(ruleSets, r.funcMap)
+	}
+
+	var mu sync.Mutex
+	var plans []RulePlan
+	r.evaluateRuleSets(ctx, ruleSets, func(rule Rule, condCtx ConditionContext, response ConditionResponse) {
+		plan := RulePlan{
+			RuleID:          rule.RuleID,
+			Matched:         response.Matched,
+			Incidents:       response.Incidents,
+			TemplateContext: response.TemplateContext,
+		}
+		if response.Matched {
+			message, tags, err := resolvePerform(rule.Perform, templateDataFromContext(condCtx), r.funcMap, r.strictTemplates)
+			if err != nil {
+				r.logger.Error(err, "unable to resolve rule message/tags", "ruleID", rule.RuleID)
+			} else {
+				plan.Message = message
+				plan.Tags = tags
+			}
+		}
+		mu.Lock()
+		plans = append(plans, plan)
+		mu.Unlock()
+	})
+
+	return PlanResult{Plans: plans, Errors: errs}
+}
+
+// processRule evaluates a single Rule's When condition.
+func processRule(ctx context.Context, rule Rule, condCtx ConditionContext, log logr.Logger) (ConditionResponse, error) {
+	response, err := rule.When.Evaluate(ctx, log, condCtx)
+	if err != nil {
+		return ConditionResponse{}, err
+	}
+	return response, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -315,6 +316,104 @@ func TestEvaluateOrConditions(t *testing.T) {
 	}
 }
 
+func TestEvaluateNotCondition(t *testing.T) {
+
+	testCases := []struct {
+		Name      string
+		Condition ConditionEntry
+		IsError   bool
+		IsMatched bool
+	}{
+		{
+			Name: "Not of a false condition matches",
+			Condition: ConditionEntry{
+				ProviderSpecificConfig: createTestConditional(false, nil, false),
+			},
+			IsMatched: true,
+		},
+		{
+			Name: "Not of a true condition does not match",
+			Condition: ConditionEntry{
+				ProviderSpecificConfig: createTestConditional(true, nil, false),
+			},
+			IsMatched: false,
+		},
+		{
+			Name: "Not of an errored condition propagates the error",
+			Condition: ConditionEntry{
+				ProviderSpecificConfig: createTestConditional(false, fmt.Errorf("boom"), false),
+			},
+			IsError: true,
+		},
+	}
+	testString := "testing"
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rule := Rule{
+				Perform: Perform{
+					Message: Message{
+						Text: &testString,
+					},
+				},
+				When: NotCondition{Condition: tc.Condition},
+			}
+
+			ret, err := processRule(context.TODO(), rule, ConditionContext{
+				Template: make(map[string]ChainTemplate),
+			}, log)
+			if (err != nil) != tc.IsError {
+				t.Errorf("got err: %v, expected error: %v", err, tc.IsError)
+			}
+			if ret.Matched != tc.IsMatched {
+				t.Errorf("Expected to be: %v, but got: %v", tc.IsMatched, ret)
+			}
+		})
+	}
+}
+
+func TestNotConditionIgnorable(t *testing.T) {
+	not := NotCondition{
+		Condition: ConditionEntry{
+			ProviderSpecificConfig: createTestConditional(false, nil, false),
+		},
+	}
+	if !not.Ignorable() {
+		t.Errorf("expected NotCondition to be ignorable when its inner condition is ignorable")
+	}
+}
+
+func TestNotConditionDoesNotExportAs(t *testing.T) {
+	entry := ConditionEntry{
+		As: "testing",
+		ProviderSpecificConfig: NotCondition{
+			Condition: ConditionEntry{
+				ProviderSpecificConfig: testChainableConditionalAs{
+					documentedKey: "filepaths",
+					AsValue:       []string{"test.yaml"},
+				},
+			},
+		},
+	}
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	condCtx := ConditionContext{Template: make(map[string]ChainTemplate)}
+
+	ret, err := entry.evaluate(context.TODO(), log, condCtx)
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	// the inner condition matches, so NotCondition itself does not match...
+	if ret.Matched {
+		t.Errorf("expected NotCondition not to match")
+	}
+	// ...and must not have exported its As, even though one was declared.
+	if _, ok := condCtx.Template["testing"]; ok {
+		t.Errorf("expected NotCondition not to export As %q", "testing")
+	}
+}
+
 func TestChainConditions(t *testing.T) {
 
 	testCases := []struct {
@@ -676,6 +775,219 @@ func TestRuleEngine(t *testing.T) {
 	}
 }
 
+func TestMessageTemplateRendering(t *testing.T) {
+	messageTemplate := `found: {{ join ", " .findFiles.filepaths }}`
+	rule := Rule{
+		RuleID:  "templated-message",
+		Perform: Perform{Message: Message{Text: &messageTemplate}},
+		When: AndCondition{Conditions: []ConditionEntry{
+			{
+				As: "findFiles",
+				ProviderSpecificConfig: testChainableConditionalAs{
+					documentedKey: "filepaths",
+					AsValue:       []string{"a.yaml", "b.yaml"},
+				},
+			},
+		}},
+	}
+
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	ruleEngine := CreateRuleEngine(context.Background(), 10, log)
+
+	responses, errs := ruleEngine.RunRules(context.Background(), []RuleSet{{Rules: []Rule{rule}}})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected errors: %v", errs)
+	}
+	if len(responses) != 1 {
+		t.Fatalf("expected one response, got %d", len(responses))
+	}
+	want := "found: a.yaml, b.yaml"
+	if responses[0].Message != want {
+		t.Errorf("expected message %q, got %q", want, responses[0].Message)
+	}
+}
+
+func TestRegisterTemplateFunc(t *testing.T) {
+	messageTemplate := `{{ shout "hi" }}`
+	rule := Rule{
+		RuleID:  "custom-func",
+		Perform: Perform{Message: Message{Text: &messageTemplate}},
+		When:    createTestConditional(true, nil, false),
+	}
+
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	ruleEngine := CreateRuleEngine(context.Background(), 10, log)
+	ruleEngine.RegisterTemplateFunc("shout", func(s string) string {
+		return strings.ToUpper(s) + "!"
+	})
+
+	responses, errs := ruleEngine.RunRules(context.Background(), []RuleSet{{Rules: []Rule{rule}}})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected errors: %v", errs)
+	}
+	if len(responses) != 1 || responses[0].Message != "HI!" {
+		t.Errorf("expected custom template func to render \"HI!\", got %v", responses)
+	}
+}
+
+func TestStrictTemplatesFailOnMissingKey(t *testing.T) {
+	messageTemplate := `{{ .doesNotExist.filepaths }}`
+	rule := Rule{
+		RuleID:  "missing-key",
+		Perform: Perform{Message: Message{Text: &messageTemplate}},
+		When:    createTestConditional(true, nil, false),
+	}
+
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	ruleEngine := CreateRuleEngine(context.Background(), 10, log, WithStrictTemplates(true))
+
+	responses, errs := ruleEngine.RunRules(context.Background(), []RuleSet{{Rules: []Rule{rule}}})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected top-level errors: %v", errs)
+	}
+	if len(responses) != 0 {
+		t.Errorf("expected the rule to be skipped when its template references a missing key, got %v", responses)
+	}
+}
+
+func TestRunRulesWithOpts(t *testing.T) {
+	woo := "WOO"
+	securityTag := "Category=security,network"
+	ruleSets := []RuleSet{
+		{
+			Name: "java",
+			Rules: []Rule{
+				{
+					RuleID:  "java-security-001",
+					Perform: Perform{Message: Message{Text: &woo}, Tag: &securityTag},
+					When:    createTestConditional(true, nil, false),
+				},
+				{
+					RuleID:  "java-experimental-001",
+					Perform: Perform{Message: Message{Text: &woo}},
+					When:    createTestConditional(true, nil, false),
+				},
+			},
+		},
+	}
+
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	ruleEngine := CreateRuleEngine(context.Background(), 10, log)
+
+	responses, errs, targetResults := ruleEngine.RunRulesWithOpts(context.Background(), ruleSets, ExecuteOpts{
+		Targets: []RuleTarget{
+			{Tag: "Category=security"},
+			{RuleID: "no-such-rule"},
+		},
+	})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected errors: %v", errs)
+	}
+	if len(responses) != 1 || responses[0].RuleID != "java-security-001" {
+		t.Errorf("expected only java-security-001 to run, got %v", responses)
+	}
+	if len(targetResults) != 2 {
+		t.Fatalf("expected a TargetResult per target, got %d", len(targetResults))
+	}
+	if targetResults[0].MatchedRules != 1 {
+		t.Errorf("expected the tag target to match 1 rule, got %d", targetResults[0].MatchedRules)
+	}
+	if targetResults[1].MatchedRules != 0 {
+		t.Errorf("expected the typo'd RuleID target to match 0 rules, got %d", targetResults[1].MatchedRules)
+	}
+}
+
+func TestRunRulesWithOptsTagTargetSkipsInvalidRuleInstead(t *testing.T) {
+	broken := "{{ .unclosed"
+	good := "good-rule"
+	whateverTag := "Category=whatever"
+	ruleSets := []RuleSet{
+		{
+			Rules: []Rule{
+				{
+					RuleID:  "broken-template",
+					Perform: Perform{Message: Message{Text: &broken}},
+					When:    createTestConditional(true, nil, false),
+				},
+				{
+					RuleID:  "good-rule",
+					Perform: Perform{Message: Message{Text: &good}, Tag: &whateverTag},
+					When:    createTestConditional(true, nil, false),
+				},
+			},
+		},
+	}
+
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	ruleEngine := CreateRuleEngine(context.Background(), 10, log)
+
+	responses, errs, targetResults := ruleEngine.RunRulesWithOpts(context.Background(), ruleSets, ExecuteOpts{
+		Targets: []RuleTarget{{Tag: "Category=whatever"}},
+	})
+	if len(errs) != 0 {
+		t.Fatalf("expected the broken rule to be skipped rather than aborting the run, got errs=%v", errs)
+	}
+	if len(responses) != 1 || responses[0].RuleID != "good-rule" {
+		t.Errorf("expected good-rule's response despite the other rule's broken template, got %v", responses)
+	}
+	if len(targetResults) != 1 {
+		t.Fatalf("expected a TargetResult for the tag target, got %d", len(targetResults))
+	}
+}
+
+func TestPlanRules(t *testing.T) {
+	woo := "WOO"
+	wooFalse := "WOO - False"
+	ruleSets := []RuleSet{
+		{
+			Rules: []Rule{
+				{
+					RuleID:  "matches",
+					Perform: Perform{Message: Message{Text: &woo}},
+					When:    createTestConditional(true, nil, false),
+				},
+				{
+					RuleID:  "does-not-match",
+					Perform: Perform{Message: Message{Text: &wooFalse}},
+					When:    createTestConditional(false, nil, false),
+				},
+			},
+		},
+	}
+
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	ruleEngine := CreateRuleEngine(context.Background(), 10, log)
+
+	result := ruleEngine.PlanRules(context.Background(), ruleSets)
+	if len(result.Plans) != 2 {
+		t.Fatalf("expected a plan for every rule, got %d", len(result.Plans))
+	}
+
+	byID := map[string]RulePlan{}
+	for _, p := range result.Plans {
+		byID[p.RuleID] = p
+	}
+
+	if !byID["matches"].Matched {
+		t.Errorf("expected rule %q to be planned as matched", "matches")
+	}
+	if byID["matches"].Message != woo {
+		t.Errorf("expected resolved message %q, got %q", woo, byID["matches"].Message)
+	}
+	if byID["does-not-match"].Matched {
+		t.Errorf("expected rule %q to be planned as not matched", "does-not-match")
+	}
+	if byID["does-not-match"].Message != "" {
+		t.Errorf("expected no message for an unmatched rule, got %q", byID["does-not-match"].Message)
+	}
+}
+
 func Test_parseTagsFromPerformString(t *testing.T) {
 	tests := []struct {
 		name      string
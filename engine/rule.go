@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RuleSet groups a collection of related Rules, typically loaded from a
+// single rule file.
+type RuleSet struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Rules       []Rule `yaml:"rules"`
+}
+
+// Rule is a single When/Perform pair: When determines whether the rule
+// matches, Perform describes what to report when it does. Unmarshalling a
+// Rule from YAML dispatches its when block through the same and/or/not and
+// registered-provider-type handling as ConditionEntry - see
+// RegisterConditionType and ConditionEntry.UnmarshalYAML.
+type Rule struct {
+	RuleID  string
+	Labels  []string
+	When    Conditional
+	Perform Perform
+}
+
+// Perform describes the side effects of a matched Rule: the message to
+// emit and, optionally, the tags to apply.
+type Perform struct {
+	Message Message `yaml:"message"`
+	Tag     *string `yaml:"tag,omitempty"`
+}
+
+// Message is the user-facing text reported for a matched incident.
+type Message struct {
+	Text *string  `yaml:"text,omitempty"`
+	Link []string `yaml:"link,omitempty"`
+}
+
+// resolvePerform renders a matched Rule's Perform block: the message text
+// and any tags declared via Tag, both of which are text/template strings
+// rendered against templateData (see templateDataFromContext) with
+// funcMap available on top of the built-in helpers. In strict mode, a
+// template referencing a key templateData doesn't have fails instead of
+// silently rendering "<no value>".
+func resolvePerform(perform Perform, templateData map[string]interface{}, funcMap template.FuncMap, strict bool) (string, []string, error) {
+	var message string
+	if perform.Message.Text != nil {
+		rendered, err := renderTemplate(*perform.Message.Text, templateData, funcMap, strict)
+		if err != nil {
+			return "", nil, err
+		}
+		message = rendered
+	}
+
+	var tags []string
+	if perform.Tag != nil {
+		rendered, err := renderTemplate(*perform.Tag, templateData, funcMap, strict)
+		if err != nil {
+			return "", nil, err
+		}
+		parsed, err := parseTagsFromPerformString(rendered)
+		if err != nil {
+			return "", nil, err
+		}
+		tags = parsed
+	}
+
+	return message, tags, nil
+}
+
+// parseTagsFromPerformString parses a Perform.Tag value into the list of
+// tags it declares. The value may either be a bare comma-separated list
+// ("test1,test2") or a category-prefixed list ("Category=test1,test2"),
+// in which case only the part after the first "=" is treated as the list
+// of tags. Whitespace around each tag is trimmed and empty entries
+// (e.g. from a trailing comma) are dropped.
+func parseTagsFromPerformString(tagString string) ([]string, error) {
+	list := tagString
+	if idx := strings.Index(tagString, "="); idx != -1 {
+		list = tagString[idx+1:]
+		if strings.Contains(list, "=") {
+			return nil, fmt.Errorf("invalid tag string %q: found more than one '='", tagString)
+		}
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
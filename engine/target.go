@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// RuleTarget selects a subset of rules for RunRulesWithOpts to evaluate.
+// Any of RuleID, RuleSetName or Tag may be set; when more than one is set
+// on the same RuleTarget, a rule must satisfy all of them. Multiple
+// RuleTargets in an ExecuteOpts are OR'd together - a rule runs if any one
+// of them selects it.
+type RuleTarget struct {
+	// RuleID matches a rule's RuleID exactly, or via a path.Match glob
+	// (e.g. "java-*").
+	RuleID string
+	// RuleSetName matches a RuleSet's Name exactly, or via a path.Match
+	// glob.
+	RuleSetName string
+	// Tag is a boolean expression over a rule's declared Perform.Tag
+	// values, e.g. "Category=security AND !experimental". Terms use the
+	// same "Category=value" syntax parseTagsFromPerformString accepts;
+	// "AND" joins terms and a "!" prefix negates one.
+	Tag string
+}
+
+// ExecuteOpts configures RunRulesWithOpts.
+type ExecuteOpts struct {
+	// Targets restricts which rules are evaluated. An empty Targets runs
+	// every rule, the same as RunRules.
+	Targets []RuleTarget
+}
+
+// TargetResult reports how many rules a single RuleTarget selected, so
+// that a Targets entry matching nothing - typically a CI misconfiguration
+// such as a typo'd rule ID - can be flagged.
+type TargetResult struct {
+	Target       RuleTarget
+	MatchedRules int
+}
+
+// filterRuleSets returns the subset of ruleSets selected by targets, along
+// with one TargetResult per target. An empty targets selects everything.
+// funcMap is the engine's registered template funcs, needed to render a
+// RuleTarget's Tag selector against a rule's own (possibly templated) Tag.
+func filterRuleSets(ruleSets []RuleSet, targets []RuleTarget, funcMap template.FuncMap) ([]RuleSet, []TargetResult, error) {
+	if len(targets) == 0 {
+		return ruleSets, nil, nil
+	}
+
+	results := make([]TargetResult, len(targets))
+	for i, t := range targets {
+		results[i] = TargetResult{Target: t}
+	}
+
+	out := make([]RuleSet, 0, len(ruleSets))
+	for _, rs := range ruleSets {
+		kept := rs
+		kept.Rules = nil
+		for _, rule := range rs.Rules {
+			added := false
+			for i, t := range targets {
+				ok, err := t.matches(rs, rule, funcMap)
+				if err != nil {
+					return nil, nil, err
+				}
+				if !ok {
+					continue
+				}
+				results[i].MatchedRules++
+				if !added {
+					kept.Rules = append(kept.Rules, rule)
+					added = true
+				}
+			}
+		}
+		if len(kept.Rules) > 0 {
+			out = append(out, kept)
+		}
+	}
+	return out, results, nil
+}
+
+func (t RuleTarget) matches(rs RuleSet, rule Rule, funcMap template.FuncMap) (bool, error) {
+	if t.RuleID != "" {
+		ok, err := path.Match(t.RuleID, rule.RuleID)
+		if err != nil {
+			return false, fmt.Errorf("invalid RuleID target %q: %w", t.RuleID, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	if t.RuleSetName != "" {
+		ok, err := path.Match(t.RuleSetName, rs.Name)
+		if err != nil {
+			return false, fmt.Errorf("invalid RuleSetName target %q: %w", t.RuleSetName, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	if t.Tag != "" {
+		expr, err := parseTagExpression(t.Tag)
+		if err != nil {
+			return false, err
+		}
+		// Target selection happens before a rule's When is evaluated, so
+		// there's no chain context yet; Tag values referencing chain data
+		// render without it here (typically as "<no value>" or empty) and
+		// so don't match. A Message.Text or Tag template that fails to
+		// execute without that data makes filtering return its error.
+		_, tags, err := resolvePerform(rule.Perform, nil, funcMap, false)
+		if err != nil {
+			return false, err
+		}
+		if !expr.matches(tags) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// tagExpr is a parsed Tag selector: every term must be satisfied (negated
+// terms must be absent) for a rule's tags to match.
+type tagExpr struct {
+	terms []tagTerm
+}
+
+type tagTerm struct {
+	tag    string
+	negate bool
+}
+
+// parseTagExpression parses a Tag selector like "Category=security AND
+// !experimental" into a tagExpr. "AND" is the only joiner and is purely
+// syntactic - every term is implicitly ANDed together whether or not it's
+// written. Each term reuses parseTagsFromPerformString's own tokenizer so
+// that a "Category=value" prefix is stripped the same way it is when the
+// tag is declared on a Rule's Perform.
+func parseTagExpression(expr string) (tagExpr, error) {
+	var terms []tagTerm
+	for _, field := range strings.Fields(expr) {
+		if strings.EqualFold(field, "AND") {
+			continue
+		}
+		negate := strings.HasPrefix(field, "!")
+		if negate {
+			field = strings.TrimPrefix(field, "!")
+		}
+		tags, err := parseTagsFromPerformString(field)
+		if err != nil {
+			return tagExpr{}, fmt.Errorf("invalid tag expression %q: %w", expr, err)
+		}
+		if len(tags) != 1 {
+			return tagExpr{}, fmt.Errorf("invalid tag expression %q: term %q must resolve to exactly one tag", expr, field)
+		}
+		terms = append(terms, tagTerm{tag: tags[0], negate: negate})
+	}
+	return tagExpr{terms: terms}, nil
+}
+
+func (e tagExpr) matches(tags []string) bool {
+	has := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		has[tag] = true
+	}
+	for _, term := range e.terms {
+		if has[term.tag] == term.negate {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// defaultTemplateFuncs are the built-in helpers available to every
+// Message.Text and Perform.Tag template, on top of whatever a caller adds
+// via RuleEngine.RegisterTemplateFunc.
+var defaultTemplateFuncs = template.FuncMap{
+	"join":     func(sep string, items []string) string { return strings.Join(items, sep) },
+	"basename": filepath.Base,
+	"relpath":  filepath.Rel,
+	"quote":    strconv.Quote,
+	"default": func(def, val string) string {
+		if val == "" {
+			return def
+		}
+		return val
+	},
+}
+
+// templateDataFromContext flattens a ConditionContext.Template into the
+// data a Message/Tag template renders against: each As name maps directly
+// to the Extras that name's condition produced, so a rule author can
+// write e.g. {{ join ", " .findJavaFiles.filepaths }}.
+func templateDataFromContext(condCtx ConditionContext) map[string]interface{} {
+	data := make(map[string]interface{}, len(condCtx.Template))
+	for name, chain := range condCtx.Template {
+		data[name] = chain.Extras
+	}
+	return data
+}
+
+// renderTemplate renders a Message.Text or Perform.Tag string as a
+// text/template against data, using funcMap in addition to
+// defaultTemplateFuncs. In strict mode, a template that references a key
+// missing from data fails instead of rendering "<no value>".
+func renderTemplate(text string, data map[string]interface{}, funcMap template.FuncMap, strict bool) (string, error) {
+	tmpl := template.New("perform").Funcs(defaultTemplateFuncs).Funcs(funcMap)
+	if strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("unable to render template %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
+
+// validateTemplateSyntax checks that text is a well-formed template
+// against funcMap, without executing it. It's what lets a template typo
+// surface from the static validator instead of mid-run.
+func validateTemplateSyntax(text string, funcMap template.FuncMap) error {
+	_, err := template.New("perform").Funcs(defaultTemplateFuncs).Funcs(funcMap).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// ValidateRule walks a Rule's When tree and reports every chain-reference
+// problem it can find statically, before the rule is ever run against a
+// provider: a From that has no earlier As producing it in scope, a From
+// that only resolves through an OrCondition whose branches don't all
+// produce that As (so the chain would be undefined had a different branch
+// matched), and a duplicate As within the same scope. A forward or mutual
+// reference (e.g. entry A's From needs entry B's As while B's From needs
+// A's) is also caught, as the ordinary "not produced by any earlier As"
+// error on whichever of the two is evaluated first - since a producer
+// must always appear earlier in evaluation order, nothing extra is needed
+// to detect it. ValidateRule also parses Perform.Message.Text and
+// Perform.Tag as templates against funcMap, so that a template typo is
+// reported here instead of at execution time.
+//
+// It does not attempt to evaluate any ProviderSpecificConfig - it only
+// looks at the shape of the From/As names declared on each ConditionEntry.
+func ValidateRule(rule Rule, funcMap template.FuncMap) []error {
+	v := &ruleValidator{ruleID: rule.RuleID}
+	v.walk(rule.When, newChainScope())
+
+	if rule.Perform.Message.Text != nil {
+		if err := validateTemplateSyntax(*rule.Perform.Message.Text, funcMap); err != nil {
+			v.errorf("invalid Message.Text: %w", err)
+		}
+	}
+	if rule.Perform.Tag != nil {
+		if err := validateTemplateSyntax(*rule.Perform.Tag, funcMap); err != nil {
+			v.errorf("invalid Tag: %w", err)
+		}
+	}
+
+	return v.errors
+}
+
+// ValidateRuleSets runs ValidateRule over every Rule in every RuleSet and
+// returns the combined list of errors found.
+func ValidateRuleSets(ruleSets []RuleSet, funcMap template.FuncMap) []error {
+	var errs []error
+	for _, rs := range ruleSets {
+		for _, rule := range rs.Rules {
+			errs = append(errs, ValidateRule(rule, funcMap)...)
+		}
+	}
+	return errs
+}
+
+type ruleValidator struct {
+	ruleID string
+	errors []error
+}
+
+func (v *ruleValidator) errorf(format string, args ...interface{}) {
+	v.errors = append(v.errors, fmt.Errorf("rule %q: "+format, append([]interface{}{v.ruleID}, args...)...))
+}
+
+// chainScope tracks, at a given point in the walk, which As names are
+// guaranteed to have been produced by anything evaluated so far, and
+// which are only partial: declared by some but not all of the branches of
+// an OrCondition already walked, so a From resolving through partial is
+// only undefined sometimes - exactly the case worth flagging, and only
+// once something actually tries to consume it.
+type chainScope struct {
+	guaranteed map[string]bool
+	partial    map[string]bool
+}
+
+func newChainScope() chainScope {
+	return chainScope{guaranteed: map[string]bool{}, partial: map[string]bool{}}
+}
+
+func (s chainScope) clone() chainScope {
+	out := newChainScope()
+	for k := range s.guaranteed {
+		out.guaranteed[k] = true
+	}
+	for k := range s.partial {
+		out.partial[k] = true
+	}
+	return out
+}
+
+// checkFrom reports an error for entry's From reference, if any, against
+// the current scope - either because nothing produces it at all, or
+// because only some branches of an earlier OrCondition do.
+func (v *ruleValidator) checkFrom(entry ConditionEntry, scope chainScope) {
+	if entry.From == "" {
+		return
+	}
+	switch {
+	case scope.guaranteed[entry.From]:
+		// ok
+	case scope.partial[entry.From]:
+		v.errorf("condition entry %q references As %q, which an earlier OrCondition only produces on some of its branches - the chain would be undefined had a different branch matched", entryID(entry), entry.From)
+	default:
+		v.errorf("condition entry %q references From %q which is not produced by any earlier As in the same rule", entryID(entry), entry.From)
+	}
+}
+
+// walk visits the tree rooted at cond against scope, and returns what cond
+// is known to produce - in both the guaranteed and partial sense - should
+// it match.
+func (v *ruleValidator) walk(cond Conditional, scope chainScope) chainScope {
+	switch c := cond.(type) {
+	case AndCondition:
+		return v.walkEntries(c.Conditions, scope, false)
+	case OrCondition:
+		return v.walkEntries(c.Conditions, scope, true)
+	case NotCondition:
+		// A Not exports nothing - it can't chain an As out - but its
+		// inner From still needs to resolve against the enclosing scope.
+		v.checkFrom(c.Condition, scope)
+		v.walk(c.Condition.ProviderSpecificConfig, scope)
+		return newChainScope()
+	default:
+		return newChainScope()
+	}
+}
+
+// walkEntries visits a composite's ConditionEntry list in order, threading
+// scope forward so that a later entry's From can see an earlier entry's
+// As. For an AndCondition every entry's As is added straight to the
+// guaranteed set, available to both later siblings and the parent. For an
+// OrCondition, an entry's As is only ever partial to its siblings (any one
+// branch might be the one that matches), and is promoted to guaranteed for
+// the parent only if every branch produces it.
+func (v *ruleValidator) walkEntries(entries []ConditionEntry, outer chainScope, isOr bool) chainScope {
+	local := outer.clone()
+	var branches []chainScope
+
+	for _, entry := range entries {
+		v.checkFrom(entry, local)
+
+		branch := v.walk(entry.ProviderSpecificConfig, local)
+		if entry.As != "" {
+			if local.guaranteed[entry.As] || local.partial[entry.As] {
+				v.errorf("duplicate As %q in the same scope", entry.As)
+			}
+			// An As on a NotCondition entry is never exported.
+			if _, isNot := entry.ProviderSpecificConfig.(NotCondition); !isNot {
+				branch.guaranteed[entry.As] = true
+			}
+		}
+		branches = append(branches, branch)
+
+		if isOr {
+			for k := range branch.guaranteed {
+				local.partial[k] = true
+			}
+			for k := range branch.partial {
+				local.partial[k] = true
+			}
+		} else {
+			for k := range branch.guaranteed {
+				local.guaranteed[k] = true
+			}
+			for k := range branch.partial {
+				local.partial[k] = true
+			}
+		}
+	}
+
+	if !isOr {
+		return local
+	}
+
+	// An As is only guaranteed to the parent if every branch produces it -
+	// a branch that doesn't match won't have produced it.
+	guaranteed := map[string]bool{}
+	if len(branches) > 0 {
+		for k := range branches[0].guaranteed {
+			guaranteed[k] = true
+		}
+		for _, b := range branches[1:] {
+			for k := range guaranteed {
+				if !b.guaranteed[k] {
+					delete(guaranteed, k)
+				}
+			}
+		}
+	}
+
+	result := outer.clone()
+	for k := range guaranteed {
+		result.guaranteed[k] = true
+	}
+	for _, b := range branches {
+		for k := range b.guaranteed {
+			if !guaranteed[k] {
+				result.partial[k] = true
+			}
+		}
+		for k := range b.partial {
+			result.partial[k] = true
+		}
+	}
+	return result
+}
+
+func entryID(entry ConditionEntry) string {
+	if entry.As != "" {
+		return entry.As
+	}
+	if entry.From != "" {
+		return entry.From
+	}
+	return "<unnamed>"
+}
@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidateRule(t *testing.T) {
+
+	testCases := []struct {
+		Name    string
+		Rule    Rule
+		NumErrs int
+	}{
+		{
+			Name: "ok chain",
+			Rule: Rule{
+				RuleID: "ok-chain",
+				When: AndCondition{Conditions: []ConditionEntry{
+					{As: "a", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+					{From: "a", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+				}},
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "missing From",
+			Rule: Rule{
+				RuleID: "missing-from",
+				When: AndCondition{Conditions: []ConditionEntry{
+					{From: "a", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+				}},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "duplicate As",
+			Rule: Rule{
+				RuleID: "duplicate-as",
+				When: AndCondition{Conditions: []ConditionEntry{
+					{As: "a", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+					{As: "a", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+				}},
+			},
+			NumErrs: 1,
+		},
+		{
+			Name: "or branch does not guarantee As, nothing consumes it",
+			Rule: Rule{
+				RuleID: "or-undefined-as-unused",
+				When: OrCondition{Conditions: []ConditionEntry{
+					{As: "a", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+					{ProviderSpecificConfig: createTestConditional(true, nil, false)},
+				}},
+			},
+			NumErrs: 0,
+		},
+		{
+			Name: "or branch does not guarantee As, a later From consumes it",
+			Rule: Rule{
+				RuleID: "or-undefined-as-used",
+				When: AndCondition{Conditions: []ConditionEntry{
+					{ProviderSpecificConfig: OrCondition{Conditions: []ConditionEntry{
+						{As: "a", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+						{ProviderSpecificConfig: createTestConditional(true, nil, false)},
+					}}},
+					{From: "a", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+				}},
+			},
+			NumErrs: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			errs := ValidateRule(tc.Rule, template.FuncMap{})
+			if len(errs) != tc.NumErrs {
+				t.Errorf("expected %d errors, got %d: %v", tc.NumErrs, len(errs), errs)
+			}
+		})
+	}
+}
+
+func TestValidateRuleSets(t *testing.T) {
+	ruleSets := []RuleSet{
+		{
+			Name: "rs",
+			Rules: []Rule{
+				{
+					RuleID: "ok",
+					When:   createTestConditional(true, nil, false),
+				},
+				{
+					RuleID: "bad",
+					When: AndCondition{Conditions: []ConditionEntry{
+						{From: "missing", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+					}},
+				},
+			},
+		},
+	}
+
+	errs := ValidateRuleSets(ruleSets, template.FuncMap{})
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error across both rules, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestRunRulesWithOptsStrictRejectsInvalidChain(t *testing.T) {
+	ruleSets := []RuleSet{
+		{
+			Rules: []Rule{
+				{
+					RuleID: "bad-chain",
+					When: AndCondition{Conditions: []ConditionEntry{
+						{From: "missing", ProviderSpecificConfig: createTestConditional(true, nil, false)},
+					}},
+				},
+			},
+		},
+	}
+
+	logrusLog := logrus.New()
+	log := logrusr.New(logrusLog)
+	ruleEngine := CreateRuleEngine(context.Background(), 10, log, WithStrict(true))
+
+	responses, errs, _ := ruleEngine.RunRulesWithOpts(context.Background(), ruleSets, ExecuteOpts{})
+	if len(errs) == 0 {
+		t.Fatalf("expected strict mode to reject the run with a validation error")
+	}
+	if len(responses) != 0 {
+		t.Errorf("expected no responses when strict mode rejects the run, got %v", responses)
+	}
+}
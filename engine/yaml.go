@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConditionFactory returns a fresh, empty Conditional for a provider's
+// condition type, ready for ConditionEntry.UnmarshalYAML to decode a rule's
+// YAML into. factory must return a new value each call.
+type ConditionFactory func() Conditional
+
+var conditionTypes = map[string]ConditionFactory{}
+
+// RegisterConditionType makes a provider-specific condition type available
+// to YAML rule files under the given name, alongside the built-in "and",
+// "or" and "not" composites: a when block can write `<name>: {...}` and
+// have it decode into whatever factory returns. Without this, a
+// ProviderSpecificConfig can only be constructed from Go, so a rule using
+// it can't be authored as YAML at all.
+func RegisterConditionType(name string, factory ConditionFactory) {
+	conditionTypes[name] = factory
+}
+
+// UnmarshalYAML decodes a when-block mapping into a Rule's When, dispatching
+// through decodeConditional the same way ConditionEntry.UnmarshalYAML does,
+// but without an enclosing from/as (the rule's top-level when has neither).
+func (r *Rule) UnmarshalYAML(value *yaml.Node) error {
+	var raw struct {
+		RuleID  string    `yaml:"ruleID"`
+		Labels  []string  `yaml:"labels,omitempty"`
+		When    yaml.Node `yaml:"when"`
+		Perform Perform   `yaml:"perform"`
+	}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+
+	var whenRaw map[string]yaml.Node
+	if err := raw.When.Decode(&whenRaw); err != nil {
+		return fmt.Errorf("decoding when: %w", err)
+	}
+	cond, err := decodeConditional(whenRaw)
+	if err != nil {
+		return fmt.Errorf("decoding when: %w", err)
+	}
+
+	r.RuleID = raw.RuleID
+	r.Labels = raw.Labels
+	r.When = cond
+	r.Perform = raw.Perform
+	return nil
+}
+
+// UnmarshalYAML decodes a single When-tree node: from/as stay ordinary
+// fields, and whatever's left (and/or/not, or a registered provider
+// condition type) becomes ProviderSpecificConfig via decodeConditional.
+func (c *ConditionEntry) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]yaml.Node
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+
+	if n, ok := raw["from"]; ok {
+		if err := n.Decode(&c.From); err != nil {
+			return fmt.Errorf("decoding from: %w", err)
+		}
+		delete(raw, "from")
+	}
+	if n, ok := raw["as"]; ok {
+		if err := n.Decode(&c.As); err != nil {
+			return fmt.Errorf("decoding as: %w", err)
+		}
+		delete(raw, "as")
+	}
+
+	cond, err := decodeConditional(raw)
+	if err != nil {
+		return err
+	}
+	c.ProviderSpecificConfig = cond
+	return nil
+}
+
+// decodeConditional builds the Conditional named by raw's one remaining
+// key: "and" and "or" each decode a list of nested ConditionEntry, "not"
+// decodes a single nested ConditionEntry, and anything else is looked up in
+// conditionTypes as a provider-specific condition type.
+func decodeConditional(raw map[string]yaml.Node) (Conditional, error) {
+	if len(raw) != 1 {
+		return nil, fmt.Errorf("condition must declare exactly one of and, or, not, or a registered provider condition type, found %d", len(raw))
+	}
+
+	for name, node := range raw {
+		node := node
+		switch name {
+		case "and":
+			var entries []ConditionEntry
+			if err := node.Decode(&entries); err != nil {
+				return nil, fmt.Errorf("decoding and: %w", err)
+			}
+			return AndCondition{Conditions: entries}, nil
+		case "or":
+			var entries []ConditionEntry
+			if err := node.Decode(&entries); err != nil {
+				return nil, fmt.Errorf("decoding or: %w", err)
+			}
+			return OrCondition{Conditions: entries}, nil
+		case "not":
+			var entry ConditionEntry
+			if err := node.Decode(&entry); err != nil {
+				return nil, fmt.Errorf("decoding not: %w", err)
+			}
+			return NotCondition{Condition: entry}, nil
+		default:
+			factory, ok := conditionTypes[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown condition type %q - register it with RegisterConditionType before unmarshalling rules that use it", name)
+			}
+			cond := factory()
+			if err := node.Decode(cond); err != nil {
+				return nil, fmt.Errorf("decoding %q condition: %w", name, err)
+			}
+			return cond, nil
+		}
+	}
+	panic("unreachable")
+}
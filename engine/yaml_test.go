@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+)
+
+type testYAMLConditional struct {
+	Pattern string `yaml:"pattern"`
+}
+
+func (t *testYAMLConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	return ConditionResponse{Matched: true}, nil
+}
+
+func (t *testYAMLConditional) Ignorable() bool {
+	return false
+}
+
+func TestConditionEntryUnmarshalYAML(t *testing.T) {
+	RegisterConditionType("testprovider.test", func() Conditional { return &testYAMLConditional{} })
+
+	testCases := []struct {
+		Name    string
+		YAML    string
+		IsError bool
+	}{
+		{
+			Name: "provider-specific condition",
+			YAML: `
+as: found
+testprovider.test:
+  pattern: foo
+`,
+		},
+		{
+			Name: "not block",
+			YAML: `
+not:
+  from: found
+  testprovider.test:
+    pattern: foo
+`,
+		},
+		{
+			Name: "and block",
+			YAML: `
+and:
+  - testprovider.test:
+      pattern: foo
+  - testprovider.test:
+      pattern: bar
+`,
+		},
+		{
+			Name: "or block",
+			YAML: `
+or:
+  - testprovider.test:
+      pattern: foo
+  - testprovider.test:
+      pattern: bar
+`,
+		},
+		{
+			Name: "unknown condition type",
+			YAML: `
+nosuchprovider.test:
+  pattern: foo
+`,
+			IsError: true,
+		},
+		{
+			Name: "ambiguous condition",
+			YAML: `
+as: found
+and:
+  - testprovider.test:
+      pattern: foo
+testprovider.test:
+  pattern: foo
+`,
+			IsError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var entry ConditionEntry
+			err := yaml.Unmarshal([]byte(tc.YAML), &entry)
+			if tc.IsError {
+				if err == nil {
+					t.Errorf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entry.ProviderSpecificConfig == nil {
+				t.Errorf("expected ProviderSpecificConfig to be populated")
+			}
+		})
+	}
+}
+
+func TestRuleUnmarshalYAML(t *testing.T) {
+	RegisterConditionType("testprovider.test", func() Conditional { return &testYAMLConditional{} })
+
+	ruleYAML := `
+ruleID: test-rule-001
+labels:
+  - "konveyor.io/source=test"
+when:
+  and:
+    - as: found
+      testprovider.test:
+        pattern: foo
+    - from: found
+      testprovider.test:
+        pattern: bar
+perform:
+  message:
+    text: "found it"
+  tag: "Category=testing"
+`
+
+	var rule Rule
+	if err := yaml.Unmarshal([]byte(ruleYAML), &rule); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.RuleID != "test-rule-001" {
+		t.Errorf("expected RuleID %q, got %q", "test-rule-001", rule.RuleID)
+	}
+	and, ok := rule.When.(AndCondition)
+	if !ok {
+		t.Fatalf("expected When to decode into an AndCondition, got %T", rule.When)
+	}
+	if len(and.Conditions) != 2 {
+		t.Fatalf("expected 2 nested conditions, got %d", len(and.Conditions))
+	}
+	if and.Conditions[0].As != "found" || and.Conditions[1].From != "found" {
+		t.Errorf("expected the chain's As/From to round-trip, got %+v", and.Conditions)
+	}
+	if rule.Perform.Message.Text == nil || *rule.Perform.Message.Text != "found it" {
+		t.Errorf("expected Perform.Message.Text to decode, got %+v", rule.Perform.Message)
+	}
+
+	if errs := ValidateRule(rule, nil); len(errs) != 0 {
+		t.Errorf("expected the round-tripped rule to validate cleanly, got %v", errs)
+	}
+}